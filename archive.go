@@ -0,0 +1,319 @@
+package ramfs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//PAX record keys used to round-trip the parts of a File/Directory that a plain tar header can't
+//carry: our Read/Write flags are independent of the unix mode bits, and Created has no standard
+//tar field at all
+const (
+	paxRead     = "RAMFS.read"
+	paxWrite    = "RAMFS.write"
+	paxCreated  = "RAMFS.created"
+	paxModified = "RAMFS.modified"
+)
+
+//MarshalTar writes every directory and file in the tree to w as a PAX-format tar archive.
+//Permissions and Created/Modified timestamps are stored as PAX extended header records so
+//UnmarshalTar can restore them exactly. Unlike Snapshot, every lazily-mounted node that hasn't
+//been touched yet is materialized before being written, so the archive has real content rather
+//than an empty placeholder; a node mounted by a concurrent MountLazy after materialization starts
+//may still be written unmaterialized, same as any other concurrent mutation during a walk
+func (fs *RAMFileSystem) MarshalTar(w io.Writer) error {
+	if err := fs.materializeAll(); err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	dirPaths := make([]string, 0, len(fs.directories))
+	for path := range fs.directories {
+		dirPaths = append(dirPaths, path)
+	}
+	sort.Strings(dirPaths)
+
+	for _, dirPath := range dirPaths {
+		dir := fs.directories[dirPath]
+		dir.mu.RLock()
+
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     tarName(dirPath, true),
+			Mode:     0755,
+			ModTime:  time.Unix(0, dir.Modified),
+			Format:   tar.FormatPAX,
+			PAXRecords: map[string]string{
+				paxRead:     strconv.FormatBool(dir.Read),
+				paxWrite:    strconv.FormatBool(dir.Write),
+				paxCreated:  strconv.FormatInt(dir.Created, 10),
+				paxModified: strconv.FormatInt(dir.Modified, 10),
+			},
+		})
+		if err != nil {
+			dir.mu.RUnlock()
+			return err
+		}
+
+		for _, file := range dir.Files {
+			if err := writeFileToTar(tw, dirPath, file); err != nil {
+				dir.mu.RUnlock()
+				return err
+			}
+		}
+
+		dir.mu.RUnlock()
+	}
+
+	return tw.Close()
+}
+
+//materializeAll forces every lazily-mounted node in the tree to load its content/children, so a
+//full walk of the tree (as MarshalTar does) sees real data instead of an unmaterialized
+//placeholder. Materializing a directory can itself reveal new lazy subdirectories, so this keeps
+//sweeping until a full pass finds nothing left to materialize
+func (fs *RAMFileSystem) materializeAll() error {
+	seen := make(map[string]bool)
+
+	for {
+		fs.mu.RLock()
+		var pending []string
+		for path := range fs.directories {
+			if !seen[path] {
+				pending = append(pending, path)
+			}
+		}
+		fs.mu.RUnlock()
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		for _, path := range pending {
+			seen[path] = true
+
+			fs.mu.RLock()
+			dir, ok := fs.directories[path]
+			fs.mu.RUnlock()
+			if !ok {
+				continue
+			}
+
+			if err := fs.ensureListed(dir, path); err != nil {
+				return err
+			}
+
+			dir.mu.RLock()
+			files := append([]*File(nil), dir.Files...)
+			dir.mu.RUnlock()
+
+			for _, file := range files {
+				if err := file.ensureLoaded(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func writeFileToTar(tw *tar.Writer, dirPath string, file *File) error {
+	file.mu.RLock()
+	defer file.mu.RUnlock()
+
+	content := file.Content()
+
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     tarName(joinPath(dirPath, file.Name), false),
+		Size:     int64(len(content)),
+		Mode:     0644,
+		ModTime:  time.Unix(0, file.Modified),
+		Format:   tar.FormatPAX,
+		PAXRecords: map[string]string{
+			paxRead:     strconv.FormatBool(file.Read),
+			paxWrite:    strconv.FormatBool(file.Write),
+			paxCreated:  strconv.FormatInt(file.Created, 10),
+			paxModified: strconv.FormatInt(file.Modified, 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(content)
+
+	return err
+}
+
+//MarshalTarGZ is MarshalTar followed by gzip compression
+func (fs *RAMFileSystem) MarshalTarGZ(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+
+	if err := fs.MarshalTar(gz); err != nil {
+		gz.Close()
+		return err
+	}
+
+	return gz.Close()
+}
+
+//dirPerm is a directory's PAX-recorded permissions/timestamps, applied after the whole archive
+//has been unpacked so a read-only directory in the source tree doesn't block its own children
+//from being written back in
+type dirPerm struct {
+	dir      *Directory
+	read     bool
+	write    bool
+	created  int64
+	modified int64
+}
+
+//UnmarshalTar rebuilds a RAMFileSystem from a tar archive produced by MarshalTar. Entries are
+//expected in parent-before-child order, which MarshalTar guarantees by writing paths sorted
+//lexicographically. A directory's own permissions are applied only once every entry has been
+//unpacked, so a read-only directory in the source tree doesn't block fs.WriteFile from recreating
+//its children.
+func UnmarshalTar(r io.Reader) (*RAMFileSystem, error) {
+	fs := New()
+
+	tr := tar.NewReader(r)
+
+	var pendingDirPerms []dirPerm
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		path := fsPathFromTarName(hdr.Name)
+		read, write, created, modified := nodeMetaFromHeader(hdr)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if path == "/" {
+				fs.mu.Lock()
+				root := fs.directories["/"]
+				fs.mu.Unlock()
+
+				pendingDirPerms = append(pendingDirPerms, dirPerm{dir: root, read: read, write: write, created: created, modified: modified})
+
+				continue
+			}
+
+			if err := fs.Mkdir(path); err != nil {
+				return nil, err
+			}
+
+			fs.mu.RLock()
+			dir := fs.directories[path]
+			fs.mu.RUnlock()
+
+			pendingDirPerms = append(pendingDirPerms, dirPerm{dir: dir, read: read, write: write, created: created, modified: modified})
+
+		case tar.TypeReg:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := fs.WriteFile(path, content); err != nil {
+				return nil, err
+			}
+
+			nodePath := strings.Split(path[1:], "/")
+
+			fs.mu.RLock()
+			parent, _ := fs.lookupDirectory(getParent(nodePath))
+			fs.mu.RUnlock()
+
+			parent.mu.RLock()
+			for _, file := range parent.Files {
+				if file.Name == nodePath[len(nodePath)-1] {
+					file.mu.Lock()
+					file.Read, file.Write = read, write
+					file.Created, file.Modified = created, modified
+					file.mu.Unlock()
+					break
+				}
+			}
+			parent.mu.RUnlock()
+		}
+	}
+
+	for _, p := range pendingDirPerms {
+		p.dir.mu.Lock()
+		p.dir.Read, p.dir.Write = p.read, p.write
+		p.dir.Created, p.dir.Modified = p.created, p.modified
+		p.dir.mu.Unlock()
+	}
+
+	return fs, nil
+}
+
+//UnmarshalTarGZ gunzips r then applies UnmarshalTar
+func UnmarshalTarGZ(r io.Reader) (*RAMFileSystem, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return UnmarshalTar(gz)
+}
+
+func nodeMetaFromHeader(hdr *tar.Header) (read, write bool, created, modified int64) {
+	read, write = true, true
+	created, modified = hdr.ModTime.UnixNano(), hdr.ModTime.UnixNano()
+
+	if v, ok := hdr.PAXRecords[paxRead]; ok {
+		read, _ = strconv.ParseBool(v)
+	}
+	if v, ok := hdr.PAXRecords[paxWrite]; ok {
+		write, _ = strconv.ParseBool(v)
+	}
+	if v, ok := hdr.PAXRecords[paxCreated]; ok {
+		created, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := hdr.PAXRecords[paxModified]; ok {
+		modified, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	return read, write, created, modified
+}
+
+//tarName converts an absolute fs path into a relative tar entry name, suffixing directories with "/"
+func tarName(path string, isDir bool) string {
+	name := strings.TrimPrefix(path, "/")
+	if name == "" {
+		name = "."
+	}
+
+	if isDir {
+		name += "/"
+	}
+
+	return name
+}
+
+//fsPathFromTarName is the inverse of tarName
+func fsPathFromTarName(name string) string {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(name, "./"), "/")
+	if trimmed == "" {
+		return "/"
+	}
+
+	return "/" + trimmed
+}
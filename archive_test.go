@@ -0,0 +1,134 @@
+package ramfs
+
+import (
+	"bytes"
+	"testing"
+)
+
+//TestMarshalUnmarshalTarRoundTrip checks a basic tree of files and directories survives a
+//MarshalTar/UnmarshalTar round trip byte-for-byte
+func TestMarshalUnmarshalTarRoundTrip(t *testing.T) {
+	fs := New()
+
+	if err := fs.Mkdir("/a/"); err != nil {
+		t.Fatalf("Mkdir /a: %v", err)
+	}
+	if err := fs.Mkdir("/a/b/"); err != nil {
+		t.Fatalf("Mkdir /a/b: %v", err)
+	}
+	if err := fs.WriteFile("/a/b/f.txt", []byte("hello")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := fs.WriteFile("/top.txt", []byte("world")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := fs.MarshalTar(&buf); err != nil {
+		t.Fatalf("MarshalTar: %v", err)
+	}
+
+	restored, err := UnmarshalTar(&buf)
+	if err != nil {
+		t.Fatalf("UnmarshalTar: %v", err)
+	}
+
+	content, err := restored.FileGetContents("/a/b/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/a/b/f.txt): %v", err)
+	}
+	if string(content) != "hello" {
+		t.Fatalf("content = %q, want %q", content, "hello")
+	}
+
+	content, err = restored.FileGetContents("/top.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/top.txt): %v", err)
+	}
+	if string(content) != "world" {
+		t.Fatalf("content = %q, want %q", content, "world")
+	}
+}
+
+//TestMarshalUnmarshalTarReadOnlyDirectoryWithChildren is a regression test: UnmarshalTar used to
+//apply a directory's read-only PAX permissions before writing its children, so any ordinary
+//read-only directory with contents failed to round-trip
+func TestMarshalUnmarshalTarReadOnlyDirectoryWithChildren(t *testing.T) {
+	fs := New()
+
+	if err := fs.Mkdir("/ro/"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := fs.WriteFile("/ro/f.txt", []byte("contents")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	fs.mu.RLock()
+	dir := fs.directories["/ro"]
+	fs.mu.RUnlock()
+
+	dir.mu.Lock()
+	dir.Write = false
+	dir.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := fs.MarshalTar(&buf); err != nil {
+		t.Fatalf("MarshalTar: %v", err)
+	}
+
+	restored, err := UnmarshalTar(&buf)
+	if err != nil {
+		t.Fatalf("UnmarshalTar: %v", err)
+	}
+
+	content, err := restored.FileGetContents("/ro/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/ro/f.txt): %v", err)
+	}
+	if string(content) != "contents" {
+		t.Fatalf("content = %q, want %q", content, "contents")
+	}
+
+	restored.mu.RLock()
+	restoredDir := restored.directories["/ro"]
+	restored.mu.RUnlock()
+
+	restoredDir.mu.RLock()
+	writable := restoredDir.Write
+	restoredDir.mu.RUnlock()
+
+	if writable {
+		t.Fatalf("/ro should have come back read-only")
+	}
+}
+
+//TestMarshalTarMaterializesLazyMount checks that MarshalTar writes real content for a lazily
+//mounted node that nothing has touched yet, rather than an empty placeholder
+func TestMarshalTarMaterializesLazyMount(t *testing.T) {
+	fs := New()
+	src := newFakeDataSource()
+	src.children[""] = []string{"f.txt"}
+	src.files["f.txt"] = []byte("lazy content")
+
+	if err := fs.MountLazy("/mnt/", src); err != nil {
+		t.Fatalf("MountLazy: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := fs.MarshalTar(&buf); err != nil {
+		t.Fatalf("MarshalTar: %v", err)
+	}
+
+	restored, err := UnmarshalTar(&buf)
+	if err != nil {
+		t.Fatalf("UnmarshalTar: %v", err)
+	}
+
+	content, err := restored.FileGetContents("/mnt/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/mnt/f.txt): %v", err)
+	}
+	if string(content) != "lazy content" {
+		t.Fatalf("content = %q, want %q", content, "lazy content")
+	}
+}
@@ -0,0 +1,362 @@
+package ramfs
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+//defaultMaxBlockSize is the block size new files are chunked into, mirroring Arvados' maxBlockSize
+const defaultMaxBlockSize = 64 * 1024 * 1024
+
+//newFile builds a *File backed by block storage and sets its initial content
+func newFile(name string, content []byte, read, write bool) *File {
+	file := &File{
+		Name: name,
+
+		maxBlockSize: defaultMaxBlockSize,
+
+		Read:  read,
+		Write: write,
+
+		Created:  time.Now().UnixNano(),
+		Modified: time.Now().UnixNano(),
+	}
+
+	file.setContent(content)
+
+	return file
+}
+
+//Content reassembles and returns the file's full content. The caller must hold at least f.mu
+//for reading
+func (f *File) Content() []byte {
+	content := make([]byte, 0, f.size)
+	for _, block := range f.blocks {
+		content = append(content, block...)
+	}
+
+	return content
+}
+
+//Len returns the file's logical size in bytes. The caller must hold at least f.mu for reading
+func (f *File) Len() int64 {
+	return f.size
+}
+
+//setContent discards the file's existing blocks and replaces them with p. The caller must hold f.mu
+func (f *File) setContent(p []byte) {
+	f.blocks = nil
+	f.size = 0
+
+	f.writeAt(p, 0)
+}
+
+//growTo extends the file's block storage so its logical size reaches end, zero-filling any gap.
+//The caller must hold f.mu
+func (f *File) growTo(end int64) {
+	if end <= f.size {
+		return
+	}
+
+	if f.maxBlockSize <= 0 {
+		f.maxBlockSize = defaultMaxBlockSize
+	}
+
+	if len(f.blocks) == 0 {
+		f.blocks = append(f.blocks, nil)
+	}
+
+	remaining := end - f.size
+
+	last := len(f.blocks) - 1
+	if room := f.maxBlockSize - len(f.blocks[last]); room > 0 {
+		add := room
+		if int64(add) > remaining {
+			add = int(remaining)
+		}
+		f.blocks[last] = append(f.blocks[last], make([]byte, add)...)
+		remaining -= int64(add)
+	}
+
+	for remaining > 0 {
+		add := f.maxBlockSize
+		if int64(add) > remaining {
+			add = int(remaining)
+		}
+		f.blocks = append(f.blocks, make([]byte, add))
+		remaining -= int64(add)
+	}
+
+	f.size = end
+}
+
+//readAt copies up to len(p) bytes starting at byte offset off into p. The caller must hold at
+//least f.mu for reading
+func (f *File) readAt(p []byte, off int64) (int, error) {
+	if off >= f.size {
+		return 0, io.EOF
+	}
+
+	total := 0
+	remaining := p
+	pos := off
+
+	for len(remaining) > 0 && pos < f.size {
+		blockIndex := int(pos / int64(f.maxBlockSize))
+		blockOffset := int(pos % int64(f.maxBlockSize))
+
+		if blockIndex >= len(f.blocks) {
+			break
+		}
+
+		n := copy(remaining, f.blocks[blockIndex][blockOffset:])
+
+		remaining = remaining[n:]
+		pos += int64(n)
+		total += n
+	}
+
+	return total, nil
+}
+
+//writeAt copies p into the file starting at byte offset off, growing the file (zero-filling any
+//gap) if needed. The caller must hold f.mu
+func (f *File) writeAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		f.growTo(off)
+		return 0, nil
+	}
+
+	end := off + int64(len(p))
+	f.growTo(end)
+
+	total := 0
+	remaining := p
+	pos := off
+
+	for len(remaining) > 0 {
+		blockIndex := int(pos / int64(f.maxBlockSize))
+		blockOffset := int(pos % int64(f.maxBlockSize))
+
+		n := copy(f.blocks[blockIndex][blockOffset:], remaining)
+
+		remaining = remaining[n:]
+		pos += int64(n)
+		total += n
+	}
+
+	f.Modified = time.Now().UnixNano()
+
+	return total, nil
+}
+
+//cloneBlocks returns a deep copy of blocks, byte contents included. writeAt mutates a block's
+//bytes in place when there's room for them, so a plain copy of the outer [][]byte (which only
+//duplicates the block headers) would still alias the original bytes; recordMutation's undo needs
+//a snapshot that a later in-place write can't retroactively change.
+func cloneBlocks(blocks [][]byte) [][]byte {
+	cloned := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		cloned[i] = append([]byte(nil), block...)
+	}
+
+	return cloned
+}
+
+//truncate resizes the file to size, padding with zero bytes if it grows. The caller must hold f.mu
+func (f *File) truncate(size int64) {
+	if size >= f.size {
+		f.growTo(size)
+		f.Modified = time.Now().UnixNano()
+		return
+	}
+
+	full := int(size / int64(f.maxBlockSize))
+	rem := int(size % int64(f.maxBlockSize))
+
+	keep := full
+	if rem > 0 {
+		keep++
+	}
+	if keep > len(f.blocks) {
+		keep = len(f.blocks)
+	}
+
+	f.blocks = f.blocks[:keep]
+	if keep > 0 && rem > 0 {
+		f.blocks[keep-1] = f.blocks[keep-1][:rem]
+	}
+
+	f.size = size
+	f.Modified = time.Now().UnixNano()
+}
+
+//AppendFile appends p to the file at path, creating it (with p as its initial content) if it
+//does not already exist
+func (fs *RAMFileSystem) AppendFile(path string, p []byte) error {
+	nodePath := strings.Split(cleanPath(path)[1:], "/")
+
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return err
+	}
+
+	parentPath := getParent(nodePath)
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(parentPath)
+	fs.mu.RUnlock()
+	if ok != true {
+		return ErrorsNoParentDirectory
+	}
+
+	if err := fs.ensureListed(parent, parentPath); err != nil {
+		return err
+	}
+
+	name := nodePath[len(nodePath)-1]
+
+	parent.mu.RLock()
+	for _, file := range parent.Files {
+		if file.Name == name {
+			parent.mu.RUnlock()
+
+			if err := file.ensureLoaded(); err != nil {
+				return err
+			}
+
+			file.mu.Lock()
+			defer file.mu.Unlock()
+
+			if file.Write == false {
+				return ErrorsNoWritePermission
+			}
+
+			var oldBlocks [][]byte
+			if fs.strict {
+				oldBlocks = cloneBlocks(file.blocks)
+			}
+			oldSize := file.size
+			oldModified := file.Modified
+
+			_, err := file.writeAt(p, file.size)
+			if err != nil {
+				return err
+			}
+
+			fs.recordMutation(path, func() {
+				file.mu.Lock()
+				file.blocks = oldBlocks
+				file.size = oldSize
+				file.Modified = oldModified
+				file.mu.Unlock()
+			})
+
+			return nil
+		}
+	}
+	parent.mu.RUnlock()
+
+	return fs.WriteFile(path, p)
+}
+
+//WriteAt writes p into the file at path starting at byte offset off, creating the file if it
+//does not exist and growing it (zero-filling any gap) if off+len(p) extends past the current end
+func (fs *RAMFileSystem) WriteAt(path string, p []byte, off int64) error {
+	nodePath := strings.Split(cleanPath(path)[1:], "/")
+
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return err
+	}
+
+	parentPath := getParent(nodePath)
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(parentPath)
+	fs.mu.RUnlock()
+	if ok != true {
+		return ErrorsNoParentDirectory
+	}
+
+	if err := fs.ensureListed(parent, parentPath); err != nil {
+		return err
+	}
+
+	name := nodePath[len(nodePath)-1]
+
+	parent.mu.RLock()
+	for _, file := range parent.Files {
+		if file.Name == name {
+			parent.mu.RUnlock()
+
+			if err := file.ensureLoaded(); err != nil {
+				return err
+			}
+
+			file.mu.Lock()
+			defer file.mu.Unlock()
+
+			if file.Write == false {
+				return ErrorsNoWritePermission
+			}
+
+			var oldBlocks [][]byte
+			if fs.strict {
+				oldBlocks = cloneBlocks(file.blocks)
+			}
+			oldSize := file.size
+			oldModified := file.Modified
+
+			_, err := file.writeAt(p, off)
+			if err != nil {
+				return err
+			}
+
+			fs.recordMutation(path, func() {
+				file.mu.Lock()
+				file.blocks = oldBlocks
+				file.size = oldSize
+				file.Modified = oldModified
+				file.mu.Unlock()
+			})
+
+			return nil
+		}
+	}
+	parent.mu.RUnlock()
+
+	if err := fs.WriteFile(path, nil); err != nil {
+		return err
+	}
+
+	return fs.WriteAt(path, p, off)
+}
+
+//MemorySize returns the approximate number of bytes the tree is using, summing block
+//capacities plus a small per-node bookkeeping overhead
+func (fs *RAMFileSystem) MemorySize() int64 {
+	const nodeOverhead = 128
+
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var total int64
+
+	for _, dir := range fs.directories {
+		dir.mu.RLock()
+		total += nodeOverhead
+
+		for _, file := range dir.Files {
+			file.mu.RLock()
+			total += nodeOverhead
+			for _, block := range file.blocks {
+				total += int64(cap(block))
+			}
+			file.mu.RUnlock()
+		}
+
+		dir.mu.RUnlock()
+	}
+
+	return total
+}
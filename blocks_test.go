@@ -0,0 +1,102 @@
+package ramfs
+
+import "testing"
+
+//TestFileWriteAtSpansBlockBoundary exercises growTo/writeAt/readAt across more than one block by
+//using a small maxBlockSize directly on a *File, rather than allocating tens of megabytes against
+//the real default
+func TestFileWriteAtSpansBlockBoundary(t *testing.T) {
+	f := newFile("f.txt", nil, true, true)
+	f.maxBlockSize = 4
+
+	if _, err := f.writeAt([]byte("ABCDEFGHIJ"), 0); err != nil {
+		t.Fatalf("writeAt: %v", err)
+	}
+
+	if got := f.Content(); string(got) != "ABCDEFGHIJ" {
+		t.Fatalf("Content() = %q, want %q", got, "ABCDEFGHIJ")
+	}
+
+	if len(f.blocks) < 3 {
+		t.Fatalf("expected content to be split across multiple 4-byte blocks, got %d blocks", len(f.blocks))
+	}
+
+	buf := make([]byte, 3)
+	n, err := f.readAt(buf, 3)
+	if err != nil {
+		t.Fatalf("readAt: %v", err)
+	}
+	if n != 3 || string(buf) != "DEF" {
+		t.Fatalf("readAt(off=3) = %q (n=%d), want %q", buf, n, "DEF")
+	}
+}
+
+//TestFileWriteAtPastEndZeroFillsGap checks that writing past the current end zero-pads the gap
+//instead of leaving it uninitialized
+func TestFileWriteAtPastEndZeroFillsGap(t *testing.T) {
+	f := newFile("f.txt", []byte("AB"), true, true)
+	f.maxBlockSize = 4
+
+	if _, err := f.writeAt([]byte("Z"), 5); err != nil {
+		t.Fatalf("writeAt: %v", err)
+	}
+
+	want := "AB\x00\x00\x00Z"
+	if got := f.Content(); string(got) != want {
+		t.Fatalf("Content() = %q, want %q", got, want)
+	}
+}
+
+//TestFileTruncateShrinksAndGrows covers both directions of truncate across a block boundary
+func TestFileTruncateShrinksAndGrows(t *testing.T) {
+	f := newFile("f.txt", []byte("ABCDEFGH"), true, true)
+	f.maxBlockSize = 4
+
+	f.truncate(3)
+	if got := f.Content(); string(got) != "ABC" {
+		t.Fatalf("after shrink, Content() = %q, want %q", got, "ABC")
+	}
+
+	f.truncate(6)
+	want := "ABC\x00\x00\x00"
+	if got := f.Content(); string(got) != want {
+		t.Fatalf("after grow, Content() = %q, want %q", got, want)
+	}
+}
+
+//TestAppendFileCreatesThenAppends checks AppendFile both creates a missing file and appends to an
+//existing one
+func TestAppendFileCreatesThenAppends(t *testing.T) {
+	fs := New()
+
+	if err := fs.AppendFile("/f.txt", []byte("AB")); err != nil {
+		t.Fatalf("AppendFile (create): %v", err)
+	}
+	if err := fs.AppendFile("/f.txt", []byte("CD")); err != nil {
+		t.Fatalf("AppendFile (append): %v", err)
+	}
+
+	content, err := fs.FileGetContents("/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents: %v", err)
+	}
+	if string(content) != "ABCD" {
+		t.Fatalf("content = %q, want %q", content, "ABCD")
+	}
+}
+
+//TestMemorySizeGrowsWithContent is a sanity check that MemorySize reflects what's actually stored
+func TestMemorySizeGrowsWithContent(t *testing.T) {
+	fs := New()
+
+	before := fs.MemorySize()
+
+	if err := fs.WriteFile("/f.txt", make([]byte, 1024)); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	after := fs.MemorySize()
+	if after <= before {
+		t.Fatalf("MemorySize() after write = %d, want > %d", after, before)
+	}
+}
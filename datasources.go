@@ -0,0 +1,101 @@
+package ramfs
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+//OSDataSource is a DataSource backed by a directory on the real filesystem, rooted at Root
+type OSDataSource struct {
+	Root string
+}
+
+//Load reads path (relative to Root) from disk
+func (s OSDataSource) Load(path string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.Root, filepath.FromSlash(path)))
+}
+
+//List reads the names of the directory at path (relative to Root) from disk
+func (s OSDataSource) List(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Root, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+//FSDataSource is a DataSource backed by an io/fs.FS, such as an embed.FS
+type FSDataSource struct {
+	FS fs.FS
+}
+
+//Load reads path from the underlying fs.FS
+func (s FSDataSource) Load(path string) ([]byte, error) {
+	return fs.ReadFile(s.FS, fsPath(path))
+}
+
+//List reads the names of the directory at path from the underlying fs.FS
+func (s FSDataSource) List(path string) ([]string, error) {
+	entries, err := fs.ReadDir(s.FS, fsPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func fsPath(path string) string {
+	if path == "" {
+		return "."
+	}
+
+	return path
+}
+
+//HTTPDataSource is a DataSource backed by a single HTTP GET. It has no concept of a directory, so
+//List always returns ErrorsDataSourceNotSupported
+type HTTPDataSource struct {
+	URL string
+}
+
+//Load fetches s.URL in full, ignoring path since a single URL has no children to address
+func (s HTTPDataSource) Load(path string) ([]byte, error) {
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &fs.PathError{Op: "load", Path: s.URL, Err: ErrorsFileNotFound}
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+//List always fails: an HTTPDataSource addresses a single resource, not a directory tree
+func (s HTTPDataSource) List(path string) ([]string, error) {
+	return nil, ErrorsDataSourceNotSupported
+}
@@ -0,0 +1,487 @@
+package ramfs
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+//FileHandle is an open reference to a File or Directory node, returned by Open/OpenFile/Create.
+//It implements io.ReadWriteSeeker, fs.File, fs.ReadDirFile and http.File so a RAMFileSystem can
+//be driven with http.FileServer, fs.WalkDir, or anything else that consumes the standard interfaces.
+type FileHandle struct {
+	fs   *RAMFileSystem
+	path string
+
+	file *File
+	dir  *Directory
+
+	offset int64
+	flag   int
+}
+
+//Stat returns the fs.FileInfo for the node this handle was opened on
+func (h *FileHandle) Stat() (fs.FileInfo, error) {
+	if h.dir != nil {
+		h.dir.mu.RLock()
+		defer h.dir.mu.RUnlock()
+		return newDirInfo(h.dir), nil
+	}
+
+	h.file.mu.RLock()
+	defer h.file.mu.RUnlock()
+
+	return newFileInfo(h.file), nil
+}
+
+//Read reads from the current offset into p, advancing the offset, as per io.Reader
+func (h *FileHandle) Read(p []byte) (int, error) {
+	if h.dir != nil {
+		return 0, &fs.PathError{Op: "read", Path: h.path, Err: ErrorsIsDirectory}
+	}
+
+	h.file.mu.RLock()
+	defer h.file.mu.RUnlock()
+
+	if h.file.Read == false {
+		return 0, &fs.PathError{Op: "read", Path: h.path, Err: ErrorsNoReadPermission}
+	}
+
+	n, err := h.file.readAt(p, h.offset)
+	h.offset += int64(n)
+
+	return n, err
+}
+
+//Write writes p at the current offset, growing the file as needed, as per io.Writer
+func (h *FileHandle) Write(p []byte) (int, error) {
+	if h.dir != nil {
+		return 0, &fs.PathError{Op: "write", Path: h.path, Err: ErrorsIsDirectory}
+	}
+
+	h.file.mu.Lock()
+	defer h.file.mu.Unlock()
+
+	if h.file.Write == false {
+		return 0, &fs.PathError{Op: "write", Path: h.path, Err: ErrorsNoWritePermission}
+	}
+
+	var oldBlocks [][]byte
+	if h.fs.strict {
+		oldBlocks = cloneBlocks(h.file.blocks)
+	}
+	oldSize := h.file.size
+	oldModified := h.file.Modified
+
+	n, err := h.file.writeAt(p, h.offset)
+	h.offset += int64(n)
+
+	file := h.file
+	h.fs.recordMutation(h.path, func() {
+		file.mu.Lock()
+		file.blocks = oldBlocks
+		file.size = oldSize
+		file.Modified = oldModified
+		file.mu.Unlock()
+	})
+
+	return n, err
+}
+
+//Seek moves the current offset, as per io.Seeker
+func (h *FileHandle) Seek(offset int64, whence int) (int64, error) {
+	var base int64
+
+	switch whence {
+	case io.SeekStart:
+		base = 0
+	case io.SeekCurrent:
+		base = h.offset
+	case io.SeekEnd:
+		if h.file != nil {
+			h.file.mu.RLock()
+			base = h.file.Len()
+			h.file.mu.RUnlock()
+		}
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: h.path, Err: os.ErrInvalid}
+	}
+
+	pos := base + offset
+	if pos < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: h.path, Err: os.ErrInvalid}
+	}
+
+	h.offset = pos
+
+	return h.offset, nil
+}
+
+//Truncate resizes the file's content to size, padding with zero bytes if it grows
+func (h *FileHandle) Truncate(size int64) error {
+	if h.dir != nil {
+		return &fs.PathError{Op: "truncate", Path: h.path, Err: ErrorsIsDirectory}
+	}
+
+	h.file.mu.Lock()
+	defer h.file.mu.Unlock()
+
+	if h.file.Write == false {
+		return &fs.PathError{Op: "truncate", Path: h.path, Err: ErrorsNoWritePermission}
+	}
+
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: h.path, Err: os.ErrInvalid}
+	}
+
+	var oldBlocks [][]byte
+	if h.fs.strict {
+		oldBlocks = cloneBlocks(h.file.blocks)
+	}
+	oldSize := h.file.size
+	oldModified := h.file.Modified
+
+	h.file.truncate(size)
+
+	file := h.file
+	h.fs.recordMutation(h.path, func() {
+		file.mu.Lock()
+		file.blocks = oldBlocks
+		file.size = oldSize
+		file.Modified = oldModified
+		file.mu.Unlock()
+	})
+
+	return nil
+}
+
+//Sync is a no-op for a plain RAMFileSystem; it exists so FileHandle satisfies durability-aware callers
+func (h *FileHandle) Sync() error {
+	return h.fs.Sync(h.path)
+}
+
+//Close releases the handle. There is nothing to flush for a plain RAMFileSystem
+func (h *FileHandle) Close() error {
+	return nil
+}
+
+//Readdir lists the directory's children as os.FileInfo, satisfying http.File
+func (h *FileHandle) Readdir(count int) ([]os.FileInfo, error) {
+	if h.dir == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: h.path, Err: ErrorsDirectoryNotFound}
+	}
+
+	if err := h.fs.ensureListed(h.dir, cleanPath(h.path)); err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: h.path, Err: err}
+	}
+
+	h.dir.mu.RLock()
+	infos := make([]os.FileInfo, 0, len(h.dir.Files)+len(h.dir.Directories))
+	for _, f := range h.dir.Files {
+		f.mu.RLock()
+		infos = append(infos, newFileInfo(f))
+		f.mu.RUnlock()
+	}
+	for _, d := range h.dir.Directories {
+		d.mu.RLock()
+		infos = append(infos, newDirInfo(d))
+		d.mu.RUnlock()
+	}
+	h.dir.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	if count <= 0 {
+		return infos, nil
+	}
+
+	if int64(h.offset) >= int64(len(infos)) {
+		return nil, io.EOF
+	}
+
+	end := int(h.offset) + count
+	if end > len(infos) {
+		end = len(infos)
+	}
+
+	page := infos[h.offset:end]
+	h.offset = int64(end)
+
+	return page, nil
+}
+
+//ReadDir lists the directory's children as fs.DirEntry, satisfying fs.ReadDirFile
+func (h *FileHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	infos, err := h.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+
+	return entries, nil
+}
+
+//fileInfo is an immutable fs.FileInfo snapshot of a *File, taken while its lock was held
+type fileInfo struct {
+	name     string
+	size     int64
+	mode     fs.FileMode
+	modified time.Time
+	node     *File
+}
+
+//newFileInfo snapshots f; the caller must hold at least f.mu for reading
+func newFileInfo(f *File) fileInfo {
+	return fileInfo{
+		name:     f.Name,
+		size:     f.Len(),
+		mode:     fileMode(f.Read, f.Write, false),
+		modified: time.Unix(0, f.Modified),
+		node:     f,
+	}
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return i.modified }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() interface{}   { return i.node }
+
+//dirInfo is an immutable fs.FileInfo snapshot of a *Directory, taken while its lock was held
+type dirInfo struct {
+	name     string
+	mode     fs.FileMode
+	modified time.Time
+	node     *Directory
+}
+
+//newDirInfo snapshots d; the caller must hold at least d.mu for reading
+func newDirInfo(d *Directory) dirInfo {
+	return dirInfo{
+		name:     d.Name,
+		mode:     fileMode(d.Read, d.Write, true),
+		modified: time.Unix(0, d.Modified),
+		node:     d,
+	}
+}
+
+func (i dirInfo) Name() string       { return i.name }
+func (i dirInfo) Size() int64        { return 0 }
+func (i dirInfo) Mode() fs.FileMode  { return i.mode }
+func (i dirInfo) ModTime() time.Time { return i.modified }
+func (i dirInfo) IsDir() bool        { return true }
+func (i dirInfo) Sys() interface{}   { return i.node }
+
+func fileMode(read, write, isDir bool) fs.FileMode {
+	var mode fs.FileMode
+
+	if isDir {
+		mode |= fs.ModeDir
+	}
+
+	if read {
+		mode |= 0444
+	}
+
+	if write {
+		mode |= 0222
+	}
+
+	return mode
+}
+
+//Open opens path for reading, satisfying io/fs.FS. Directories and files are both returned as a *FileHandle
+func (fs *RAMFileSystem) Open(name string) (fs.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+//OpenFile opens path honouring os.O_CREATE (create a missing file), os.O_TRUNC (empty an existing
+//file) and os.O_APPEND (start the handle's offset at the end of the file; later writes go wherever
+//the offset is). The access mode (os.O_RDONLY/O_WRONLY/O_RDWR) is not enforced: any handle can be
+//read and written, subject only to the node's own Read/Write permissions
+func (ramfs *RAMFileSystem) OpenFile(path string, flag int, perm os.FileMode) (*FileHandle, error) {
+
+	dirPath := cleanPath("/" + path)
+
+	if err := ramfs.ensureAncestorsListed(strings.Split(dirPath[1:], "/")); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	ramfs.mu.RLock()
+	dir, ok := ramfs.lookupDirectory(dirPath)
+	ramfs.mu.RUnlock()
+	if ok {
+		if err := ramfs.ensureListed(dir, dirPath); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+		}
+
+		dir.mu.RLock()
+		defer dir.mu.RUnlock()
+
+		if dir.Read == false {
+			return nil, &fs.PathError{Op: "open", Path: path, Err: ErrorsNoReadPermission}
+		}
+		return &FileHandle{fs: ramfs, path: dirPath, dir: dir}, nil
+	}
+
+	nodePath := strings.Split(dirPath[1:], "/")
+	parentPath := getParent(nodePath)
+
+	ramfs.mu.RLock()
+	parent, ok := ramfs.lookupDirectory(parentPath)
+	ramfs.mu.RUnlock()
+	if ok != true {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: ErrorsDirectoryNotFound}
+	}
+
+	if err := ramfs.ensureListed(parent, parentPath); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	name := nodePath[len(nodePath)-1]
+
+	parent.mu.RLock()
+	for _, f := range parent.Files {
+		if f.Name == name {
+			if err := f.ensureLoaded(); err != nil {
+				parent.mu.RUnlock()
+				return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+			}
+
+			f.mu.Lock()
+
+			if f.Read == false {
+				f.mu.Unlock()
+				parent.mu.RUnlock()
+				return nil, &fs.PathError{Op: "open", Path: path, Err: ErrorsNoReadPermission}
+			}
+
+			if flag&os.O_TRUNC != 0 {
+				if f.Write == false {
+					f.mu.Unlock()
+					parent.mu.RUnlock()
+					return nil, &fs.PathError{Op: "open", Path: path, Err: ErrorsNoWritePermission}
+				}
+				f.setContent(nil)
+			}
+
+			offset := int64(0)
+			if flag&os.O_APPEND != 0 {
+				offset = f.Len()
+			}
+
+			f.mu.Unlock()
+			parent.mu.RUnlock()
+
+			return &FileHandle{fs: ramfs, path: path, file: f, offset: offset, flag: flag}, nil
+		}
+	}
+	parent.mu.RUnlock()
+
+	if flag&os.O_CREATE == 0 {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: ErrorsFileNotFound}
+	}
+
+	if err := ramfs.WriteFile(dirPath, nil); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	parent.mu.RLock()
+	defer parent.mu.RUnlock()
+
+	for _, f := range parent.Files {
+		if f.Name == name {
+			return &FileHandle{fs: ramfs, path: path, file: f}, nil
+		}
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: path, Err: ErrorsFileNotFound}
+}
+
+//Create opens path for reading and writing, creating it (and truncating it if it already exists)
+func (ramfs *RAMFileSystem) Create(path string) (*FileHandle, error) {
+	return ramfs.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+//Stat returns the fs.FileInfo for path, satisfying io/fs.StatFS
+func (ramfs *RAMFileSystem) Stat(name string) (fs.FileInfo, error) {
+	h, err := ramfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+
+	return h.Stat()
+}
+
+//ReadDir lists the entries of path, satisfying io/fs.ReadDirFS
+func (ramfs *RAMFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	dirPath := cleanPath("/" + name)
+
+	if err := ramfs.ensureAncestorsListed(strings.Split(dirPath[1:], "/")); err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	dir, err := ramfs.getDir(dirPath)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	h := &FileHandle{fs: ramfs, path: dirPath, dir: dir}
+
+	return h.ReadDir(-1)
+}
+
+//Glob returns every path in the tree matching pattern, satisfying io/fs.GlobFS
+func (ramfs *RAMFileSystem) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	ramfs.mu.RLock()
+	defer ramfs.mu.RUnlock()
+
+	var matches []string
+	for p, dir := range ramfs.directories {
+		if ok, _ := path.Match(pattern, strings.TrimPrefix(p, "/")); ok {
+			matches = append(matches, p)
+		}
+
+		dir.mu.RLock()
+		for _, f := range dir.Files {
+			full := path.Join(p, f.Name)
+			if ok, _ := path.Match(pattern, strings.TrimPrefix(full, "/")); ok {
+				matches = append(matches, full)
+			}
+		}
+		dir.mu.RUnlock()
+	}
+
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+//httpFileSystem adapts a *RAMFileSystem to http.FileSystem
+type httpFileSystem struct {
+	fs *RAMFileSystem
+}
+
+func (h httpFileSystem) Open(name string) (http.File, error) {
+	return h.fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+//HTTPFileSystem returns an http.FileSystem backed by this RAMFileSystem, suitable for http.FileServer
+func (ramfs *RAMFileSystem) HTTPFileSystem() http.FileSystem {
+	return httpFileSystem{fs: ramfs}
+}
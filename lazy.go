@@ -0,0 +1,252 @@
+package ramfs
+
+import (
+	"strings"
+	"time"
+)
+
+//DataSource backs a lazily-mounted node. Load and List are called the first time a node's content
+//(for a file) or children (for a directory) are needed, and the result is cached on the node
+//afterwards. Goroutines that reach the same unmaterialized node at the same time may each call the
+//source; only one result is kept. A source that can't produce one of the two (for example a source
+//backed by a single HTTP GET) should return ErrorsDataSourceNotSupported for it.
+type DataSource interface {
+	//Load returns the full content of path, relative to the mount's root
+	Load(path string) ([]byte, error)
+
+	//List returns the immediate children of the directory at path, relative to the mount's root.
+	//Subdirectories are suffixed with "/", mirroring Rm's convention for identifying them.
+	List(path string) ([]string, error)
+}
+
+//DeferredNode is the lazy-loading state shared by a deferred File and Directory: where its
+//content/children come from, and whether they've been materialized yet.
+type DeferredNode struct {
+	source     DataSource
+	sourcePath string
+	loaded     bool
+}
+
+//MountLazy inserts a placeholder at path backed by src, to be materialized on demand. A path
+//ending in "/" is mounted as a directory whose children are listed on first Ls; any other path is
+//mounted as a file whose content is loaded on first FileGetContents.
+func (fs *RAMFileSystem) MountLazy(path string, src DataSource) error {
+	if path[len(path)-1] == '/' {
+		return fs.mountLazyDir(cleanPath(path), src)
+	}
+
+	return fs.mountLazyFile(cleanPath(path), src)
+}
+
+func (fs *RAMFileSystem) mountLazyFile(path string, src DataSource) error {
+	nodePath := strings.Split(path[1:], "/")
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(getParent(nodePath))
+	fs.mu.RUnlock()
+	if ok != true {
+		return ErrorsNoParentDirectory
+	}
+
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+
+	if parent.Write == false {
+		return ErrorsNoWritePermission
+	}
+
+	file := newFile(nodePath[len(nodePath)-1], nil, true, true)
+	file.lazy = DeferredNode{source: src}
+
+	var rebuiltParentFiles []*File
+	for _, parentFile := range parent.Files {
+		if file.Name != parentFile.Name {
+			rebuiltParentFiles = append(rebuiltParentFiles, parentFile)
+		} else if parentFile.Write == false {
+			return ErrorsNoWritePermission
+		}
+	}
+
+	parent.Files = append(rebuiltParentFiles, file)
+	parent.Modified = time.Now().UnixNano()
+
+	return nil
+}
+
+func (fs *RAMFileSystem) mountLazyDir(path string, src DataSource) error {
+	nodePath := strings.Split(path[1:], "/")
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	parent, ok := fs.lookupDirectory(getParent(nodePath))
+	if ok != true {
+		return ErrorsNoParentDirectory
+	}
+
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+
+	if parent.Write == false {
+		return ErrorsNoWritePermission
+	}
+
+	dir := &Directory{
+		Name: nodePath[len(nodePath)-1],
+
+		Read:  true,
+		Write: true,
+
+		Created:  time.Now().UnixNano(),
+		Modified: time.Now().UnixNano(),
+
+		lazy: DeferredNode{source: src},
+	}
+
+	var rebuiltParentDirectories []*Directory
+	for _, parentSubDir := range parent.Directories {
+		if dir.Name != parentSubDir.Name {
+			rebuiltParentDirectories = append(rebuiltParentDirectories, parentSubDir)
+		} else if parentSubDir.Write == false {
+			return ErrorsNoWritePermission
+		}
+	}
+
+	parent.Directories = append(rebuiltParentDirectories, dir)
+	parent.Modified = time.Now().UnixNano()
+
+	fs.directories[path] = dir
+
+	return nil
+}
+
+//ensureLoaded materializes f's content from its data source, if it has one and hasn't already
+func (f *File) ensureLoaded() error {
+	f.mu.RLock()
+	source, sourcePath, loaded := f.lazy.source, f.lazy.sourcePath, f.lazy.loaded
+	f.mu.RUnlock()
+
+	if source == nil || loaded {
+		return nil
+	}
+
+	content, err := source.Load(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lazy.loaded {
+		return nil
+	}
+
+	f.setContent(content)
+	f.lazy.loaded = true
+
+	return nil
+}
+
+//ensureListed materializes dir's children from its data source, if it has one and hasn't already.
+//path is dir's absolute, cleaned path within fs
+func (fs *RAMFileSystem) ensureListed(dir *Directory, path string) error {
+	dir.mu.RLock()
+	source, sourcePath, loaded := dir.lazy.source, dir.lazy.sourcePath, dir.lazy.loaded
+	dir.mu.RUnlock()
+
+	if source == nil || loaded {
+		return nil
+	}
+
+	names, err := source.List(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir.mu.Lock()
+	defer dir.mu.Unlock()
+
+	if dir.lazy.loaded {
+		return nil
+	}
+
+	for _, name := range names {
+		if strings.HasSuffix(name, "/") {
+			childName := strings.TrimSuffix(name, "/")
+
+			child := &Directory{
+				Name: childName,
+
+				Read:  dir.Read,
+				Write: dir.Write,
+
+				Created:  time.Now().UnixNano(),
+				Modified: time.Now().UnixNano(),
+
+				lazy: DeferredNode{source: source, sourcePath: joinSourcePath(sourcePath, childName)},
+			}
+
+			dir.Directories = append(dir.Directories, child)
+			fs.directories[joinPath(path, childName)] = child
+
+			continue
+		}
+
+		child := newFile(name, nil, dir.Read, dir.Write)
+		child.lazy = DeferredNode{source: source, sourcePath: joinSourcePath(sourcePath, name)}
+
+		dir.Files = append(dir.Files, child)
+	}
+
+	dir.lazy.loaded = true
+
+	return nil
+}
+
+//ensureAncestorsListed materializes every lazy directory from the root down to nodePath's parent,
+//inserting each level into fs.directories as it goes. A lazily-mounted tree is otherwise only
+//addressable level-by-level (fs.directories["/mnt/sub1/sub2"] doesn't exist until something has
+//already listed "/mnt/sub1", which itself requires "/mnt" to have been listed first), so a direct
+//lookup several levels into an untouched mount would otherwise fail even though the DataSource has
+//the path. nodePath's last element (the target file/directory's own name) is left for the caller's
+//usual lookup/ensureListed to resolve.
+func (fs *RAMFileSystem) ensureAncestorsListed(nodePath []string) error {
+	current := "/"
+
+	for i := 0; i < len(nodePath); i++ {
+		fs.mu.RLock()
+		dir, ok := fs.lookupDirectory(current)
+		fs.mu.RUnlock()
+		if !ok {
+			return nil
+		}
+
+		if err := fs.ensureListed(dir, current); err != nil {
+			return err
+		}
+
+		current = joinPath(current, nodePath[i])
+	}
+
+	return nil
+}
+
+func joinSourcePath(base, name string) string {
+	if base == "" {
+		return name
+	}
+
+	return base + "/" + name
+}
+
+func joinPath(dir, name string) string {
+	if dir == "/" {
+		return "/" + name
+	}
+
+	return dir + "/" + name
+}
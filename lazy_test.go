@@ -0,0 +1,125 @@
+package ramfs
+
+import "testing"
+
+//fakeDataSource is a DataSource backed by an in-memory map, with a call counter so tests can
+//assert that Load/List only ever fire once per node
+type fakeDataSource struct {
+	files    map[string][]byte
+	children map[string][]string
+
+	loadCalls map[string]int
+	listCalls map[string]int
+}
+
+func newFakeDataSource() *fakeDataSource {
+	return &fakeDataSource{
+		files:     make(map[string][]byte),
+		children:  make(map[string][]string),
+		loadCalls: make(map[string]int),
+		listCalls: make(map[string]int),
+	}
+}
+
+func (s *fakeDataSource) Load(path string) ([]byte, error) {
+	s.loadCalls[path]++
+
+	content, ok := s.files[path]
+	if !ok {
+		return nil, ErrorsFileNotFound
+	}
+
+	return content, nil
+}
+
+func (s *fakeDataSource) List(path string) ([]string, error) {
+	s.listCalls[path]++
+
+	names, ok := s.children[path]
+	if !ok {
+		return nil, ErrorsDirectoryNotFound
+	}
+
+	return names, nil
+}
+
+//TestMountLazyFileLoadsOnce checks that a lazily-mounted file's content is fetched from its
+//DataSource exactly once, on first read
+func TestMountLazyFileLoadsOnce(t *testing.T) {
+	fs := New()
+	src := newFakeDataSource()
+	src.files[""] = []byte("hello")
+
+	if err := fs.MountLazy("/f.txt", src); err != nil {
+		t.Fatalf("MountLazy: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		content, err := fs.FileGetContents("/f.txt")
+		if err != nil {
+			t.Fatalf("FileGetContents: %v", err)
+		}
+		if string(content) != "hello" {
+			t.Fatalf("content = %q, want %q", content, "hello")
+		}
+	}
+
+	if src.loadCalls[""] != 1 {
+		t.Fatalf("Load called %d times, want 1", src.loadCalls[""])
+	}
+}
+
+//TestMountLazyDirListsOnce checks that a lazily-mounted directory's children are fetched exactly
+//once, on first Ls
+func TestMountLazyDirListsOnce(t *testing.T) {
+	fs := New()
+	src := newFakeDataSource()
+	src.children[""] = []string{"a.txt", "sub/"}
+	src.files["a.txt"] = []byte("A")
+
+	if err := fs.MountLazy("/mnt/", src); err != nil {
+		t.Fatalf("MountLazy: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := fs.Ls("/mnt"); err != nil {
+			t.Fatalf("Ls: %v", err)
+		}
+	}
+
+	if src.listCalls[""] != 1 {
+		t.Fatalf("List called %d times, want 1", src.listCalls[""])
+	}
+
+	content, err := fs.FileGetContents("/mnt/a.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/mnt/a.txt): %v", err)
+	}
+	if string(content) != "A" {
+		t.Fatalf("content = %q, want %q", content, "A")
+	}
+}
+
+//TestDeepLookupMaterializesMissingAncestors is a regression test: a lazily-mounted tree used to be
+//addressable only level-by-level, so a direct deep lookup into a mount nothing had Ls'd yet would
+//fail even though the DataSource had the path
+func TestDeepLookupMaterializesMissingAncestors(t *testing.T) {
+	fs := New()
+	src := newFakeDataSource()
+	src.children[""] = []string{"sub1/"}
+	src.children["sub1"] = []string{"sub2/"}
+	src.children["sub1/sub2"] = []string{"file.txt"}
+	src.files["sub1/sub2/file.txt"] = []byte("deep")
+
+	if err := fs.MountLazy("/mnt/", src); err != nil {
+		t.Fatalf("MountLazy: %v", err)
+	}
+
+	content, err := fs.FileGetContents("/mnt/sub1/sub2/file.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/mnt/sub1/sub2/file.txt): %v", err)
+	}
+	if string(content) != "deep" {
+		t.Fatalf("content = %q, want %q", content, "deep")
+	}
+}
@@ -0,0 +1,27 @@
+package ramfs
+
+import (
+	"sync"
+)
+
+//DebugLocksPanicMode enables runtime verification that "caller must already hold the lock"
+//helpers are never called without the lock held. When true, every such helper tries, right then
+//and there, to acquire the same lock itself; if it succeeds, the caller wasn't holding it, and we
+//panic immediately instead of letting the race corrupt state silently. This is meant for tests,
+//not production use.
+var DebugLocksPanicMode = false
+
+//debugCheckLocked is called by internal helpers that require the caller to already hold mu. It is
+//a no-op unless DebugLocksPanicMode is set. The check must happen synchronously and immediately,
+//while the caller's critical section is still open; deferring it (a goroutine, a sleep) lets the
+//caller finish and release the lock first, so the check would always see it as unlocked.
+func debugCheckLocked(mu *sync.RWMutex, what string) {
+	if !DebugLocksPanicMode {
+		return
+	}
+
+	if mu.TryLock() {
+		mu.Unlock()
+		panic("ramfs: " + what + " called without the lock held")
+	}
+}
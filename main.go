@@ -4,6 +4,7 @@ import (
 	"errors"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -31,6 +32,17 @@ var (
 
 	//ErrorsNoReadPermission means either the direct or parent node has denied read access
 	ErrorsNoReadPermission = errors.New("no read permission to view contents that file/directory")
+
+	//ErrorsIsDirectory is returned when an operation that requires a file is given a directory
+	ErrorsIsDirectory = errors.New("path is a directory")
+
+	//ErrorsDirectoryNotEmpty is returned when a rename would overwrite a directory that still has
+	//files or subdirectories in it
+	ErrorsDirectoryNotEmpty = errors.New("directory is not empty")
+
+	//ErrorsInvalidRename is returned when a rename's destination is the source itself or a path
+	//nested inside it, which would otherwise create a cycle in the directory tree
+	ErrorsInvalidRename = errors.New("can not rename a directory into itself or a descendant of itself")
 )
 
 const (
@@ -44,8 +56,8 @@ const (
 type Option int
 
 //New create a new memory based file system
-func New(options ...Option) RAMFileSystem {
-	fs := RAMFileSystem{
+func New(options ...Option) *RAMFileSystem {
+	fs := &RAMFileSystem{
 		directories: make(map[string]*Directory),
 	}
 
@@ -83,11 +95,21 @@ func New(options ...Option) RAMFileSystem {
 
 //RAMFileSystem is the object which holds the whole filesystem
 type RAMFileSystem struct {
+	mu sync.RWMutex
+
 	directories map[string]*Directory
+
+	strict      bool
+	ignoreSyncs bool
+
+	pendingMu sync.Mutex
+	pending   []pendingMutation
 }
 
 //Directory is a type which contains references to subdirectories but holds files exclusively within it's property
 type Directory struct {
+	mu sync.RWMutex
+
 	Name string
 
 	Directories []*Directory
@@ -98,46 +120,62 @@ type Directory struct {
 
 	Created  int64
 	Modified int64
+
+	lazy DeferredNode
 }
 
-//File holds the content and metadata within this memory memory space
+//File holds the content and metadata within this memory memory space. Content is stored as a
+//sequence of fixed-size blocks rather than one contiguous slice, so appending to or partially
+//overwriting a large file doesn't force a full copy of the whole file on every write.
 type File struct {
+	mu sync.RWMutex
+
 	Name string
 
-	Content []byte
+	blocks       [][]byte
+	size         int64
+	maxBlockSize int
 
 	Read  bool
 	Write bool
 
 	Created  int64
 	Modified int64
+
+	lazy DeferredNode
 }
 
 //WriteFile takes in a path then checks the permissons then writes the content provided into memory
 func (fs *RAMFileSystem) WriteFile(path string, content []byte) error {
 
 	nodePath := strings.Split(cleanPath(path)[1:], "/")
-	parent, ok := fs.directories[getParent(nodePath)]
+
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return err
+	}
+
+	parentPath := getParent(nodePath)
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(parentPath)
+	fs.mu.RUnlock()
 	if ok != true {
 		return ErrorsNoParentDirectory
 	}
 
-	if parent.Write == false {
-		return ErrorsNoWritePermission
+	if err := fs.ensureListed(parent, parentPath); err != nil {
+		return err
 	}
 
-	file := &File{
-		Name: nodePath[len(nodePath)-1],
-
-		Content: content,
-
-		Read:  true,
-		Write: true,
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
 
-		Created:  time.Now().UnixNano(),
-		Modified: time.Now().UnixNano(),
+	if parent.Write == false {
+		return ErrorsNoWritePermission
 	}
 
+	file := newFile(nodePath[len(nodePath)-1], content, true, true)
+
 	var rebuiltParentFiles []*File
 	for _, parentFile := range parent.Files {
 		if file.Name != parentFile.Name {
@@ -149,11 +187,19 @@ func (fs *RAMFileSystem) WriteFile(path string, content []byte) error {
 		}
 	}
 
+	oldFiles := parent.Files
+
 	rebuiltParentFiles = append(rebuiltParentFiles, file)
 
 	parent.Files = rebuiltParentFiles
 	parent.Modified = time.Now().UnixNano()
 
+	fs.recordMutation(path, func() {
+		parent.mu.Lock()
+		parent.Files = oldFiles
+		parent.mu.Unlock()
+	})
+
 	return nil
 
 }
@@ -162,11 +208,27 @@ func (fs *RAMFileSystem) WriteFile(path string, content []byte) error {
 func (fs *RAMFileSystem) FileGetContents(path string) ([]byte, error) {
 
 	nodePath := strings.Split(cleanPath(path)[1:], "/")
-	parent, ok := fs.directories[getParent(nodePath)]
+
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return nil, err
+	}
+
+	parentPath := getParent(nodePath)
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(parentPath)
+	fs.mu.RUnlock()
 	if ok != true {
 		return nil, ErrorsNoParentDirectory
 	}
 
+	if err := fs.ensureListed(parent, parentPath); err != nil {
+		return nil, err
+	}
+
+	parent.mu.RLock()
+	defer parent.mu.RUnlock()
+
 	if parent.Read == false {
 		return nil, ErrorsNoReadPermission
 	}
@@ -174,11 +236,18 @@ func (fs *RAMFileSystem) FileGetContents(path string) ([]byte, error) {
 	for _, file := range parent.Files {
 		if file.Name == nodePath[len(nodePath)-1] {
 
+			if err := file.ensureLoaded(); err != nil {
+				return nil, err
+			}
+
+			file.mu.RLock()
+			defer file.mu.RUnlock()
+
 			if file.Read == false {
 				return nil, ErrorsNoReadPermission
 			}
 
-			return file.Content, nil
+			return file.Content(), nil
 		}
 	}
 
@@ -191,9 +260,22 @@ func (fs *RAMFileSystem) Rm(path string) error {
 
 	nodePath := strings.Split(cleanPath(path)[1:], "/")
 
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return err
+	}
+
 	if path[len(path)-1] == '/' {
+		parentPath := getParent(nodePath)
 
-		node, ok := fs.directories[cleanPath(path)]
+		fs.mu.RLock()
+		node, ok := fs.lookupDirectory(cleanPath(path))
+		if ok != true {
+			fs.mu.RUnlock()
+			return ErrorsDirectoryNotFound
+		}
+
+		parent, ok := fs.lookupDirectory(parentPath)
+		fs.mu.RUnlock()
 		if ok != true {
 			return ErrorsDirectoryNotFound
 		}
@@ -202,11 +284,18 @@ func (fs *RAMFileSystem) Rm(path string) error {
 			return ErrorsNoWritePermission
 		}
 
-		parent, ok := fs.directories[getParent(nodePath)]
-		if ok != true {
-			return ErrorsDirectoryNotFound
+		if err := fs.ensureListed(parent, parentPath); err != nil {
+			return err
 		}
 
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+
+		parent.mu.Lock()
+		defer parent.mu.Unlock()
+
+		oldDirectories := parent.Directories
+
 		var dirFolders []*Directory
 		for _, dir := range parent.Directories {
 			if dir.Name == nodePath[len(nodePath)-1] {
@@ -225,20 +314,45 @@ func (fs *RAMFileSystem) Rm(path string) error {
 
 		parent.Directories = dirFolders
 
+		fs.recordMutation(path, func() {
+			fs.mu.Lock()
+			fs.directories[cleanPath(path)] = node
+			fs.mu.Unlock()
+
+			parent.mu.Lock()
+			parent.Directories = oldDirectories
+			parent.mu.Unlock()
+		})
+
 		return nil
 	}
 
-	parent, ok := fs.directories[getParent(nodePath)]
+	parentPath := getParent(nodePath)
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(parentPath)
+	fs.mu.RUnlock()
 	if ok != true {
 		return ErrorsFileNotFound
 	}
 
+	if err := fs.ensureListed(parent, parentPath); err != nil {
+		return err
+	}
+
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+
+	oldFiles := parent.Files
+
 	var parentFiles []*File
 	for _, file := range parent.Files {
 		if file.Name == nodePath[len(nodePath)-1] {
 			if file.Write == false {
 				return ErrorsNoWritePermission
 			}
+
+			continue
 		}
 
 		parentFiles = append(parentFiles, file)
@@ -246,6 +360,12 @@ func (fs *RAMFileSystem) Rm(path string) error {
 
 	parent.Files = parentFiles
 
+	fs.recordMutation(path, func() {
+		parent.mu.Lock()
+		parent.Files = oldFiles
+		parent.mu.Unlock()
+	})
+
 	return nil
 
 }
@@ -261,11 +381,29 @@ func (fs *RAMFileSystem) Mkdir(path string) error {
 
 	nodePath := strings.Split(cleanPath(path)[1:], "/")
 
-	parent, ok := fs.directories[getParent(nodePath)]
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return err
+	}
+
+	parentPath := getParent(nodePath)
+
+	fs.mu.RLock()
+	parent, ok := fs.lookupDirectory(parentPath)
+	fs.mu.RUnlock()
 	if ok != true {
 		return ErrorsNoParentDirectory
 	}
 
+	if err := fs.ensureListed(parent, parentPath); err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+
 	if parent.Write == false {
 		return ErrorsNoWritePermission
 	}
@@ -291,29 +429,61 @@ func (fs *RAMFileSystem) Mkdir(path string) error {
 		}
 	}
 
+	oldDirectories := parent.Directories
+
 	rebuiltParentDirectories = append(rebuiltParentDirectories, dir)
 
 	parent.Directories = rebuiltParentDirectories
 	parent.Modified = time.Now().UnixNano()
 
-	fs.directories["/"+strings.Join(nodePath, "/")] = dir
+	dirPath := "/" + strings.Join(nodePath, "/")
+	replaced, hadReplaced := fs.directories[dirPath]
+	fs.directories[dirPath] = dir
+
+	fs.recordMutation(path, func() {
+		fs.mu.Lock()
+		if hadReplaced {
+			fs.directories[dirPath] = replaced
+		} else {
+			delete(fs.directories, dirPath)
+		}
+		fs.mu.Unlock()
+
+		parent.mu.Lock()
+		parent.Directories = oldDirectories
+		parent.mu.Unlock()
+	})
 
 	return nil
 }
 
 //Ls will return a list of files and directories within a given path after checking the permissions
 func (fs *RAMFileSystem) Ls(path string) ([]*File, []*Directory, error) {
+	nodePath := strings.Split(cleanPath(path)[1:], "/")
+	if err := fs.ensureAncestorsListed(nodePath); err != nil {
+		return nil, nil, err
+	}
+
 	dir, err := fs.getDir(path)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if err := fs.ensureListed(dir, cleanPath(path)); err != nil {
+		return nil, nil, err
+	}
+
+	dir.mu.RLock()
+	defer dir.mu.RUnlock()
+
 	return dir.Files, dir.Directories, nil
 }
 
 func (fs *RAMFileSystem) getDir(path string) (*Directory, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
 
-	dir, ok := fs.directories[path]
+	dir, ok := fs.lookupDirectory(path)
 	if ok != true {
 		return nil, ErrorsDirectoryNotFound
 	}
@@ -321,6 +491,16 @@ func (fs *RAMFileSystem) getDir(path string) (*Directory, error) {
 	return dir, nil
 }
 
+//lookupDirectory looks up path in the directory map. The caller must already hold fs.mu
+//(for reading or writing); when DebugLocksPanicMode is set, calling this without the lock
+//held is caught and panics instead of silently racing.
+func (fs *RAMFileSystem) lookupDirectory(path string) (*Directory, bool) {
+	debugCheckLocked(&fs.mu, "lookupDirectory")
+
+	dir, ok := fs.directories[path]
+	return dir, ok
+}
+
 func getParent(nodePath []string) string {
 
 	if len(nodePath) == 0 {
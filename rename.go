@@ -0,0 +1,295 @@
+package ramfs
+
+import (
+	"strings"
+	"time"
+)
+
+//Rename moves the file or directory at oldPath to newPath, checking write permission on both
+//parents (and, when newPath already exists, on the node being overwritten). Overwriting an
+//existing file is allowed; overwriting a non-empty directory returns ErrorsDirectoryNotEmpty;
+//renaming a file onto an existing directory returns ErrorsIsDirectory. Directory renames re-key
+//every descendant entry in the directories map while fs.mu is held, so a concurrent Ls never
+//observes a half-moved subtree.
+func (fs *RAMFileSystem) Rename(oldPath, newPath string) error {
+	oldPath = cleanPath(oldPath)
+	newPath = cleanPath(newPath)
+
+	if oldPath == "/" || newPath == "/" {
+		return ErrorsNoParentDirectory
+	}
+
+	if newPath == oldPath || strings.HasPrefix(newPath, oldPath+"/") {
+		return ErrorsInvalidRename
+	}
+
+	oldNodePath := strings.Split(oldPath[1:], "/")
+	newNodePath := strings.Split(newPath[1:], "/")
+
+	if err := fs.ensureAncestorsListed(oldNodePath); err != nil {
+		return err
+	}
+	if err := fs.ensureAncestorsListed(newNodePath); err != nil {
+		return err
+	}
+
+	oldParentPath := getParent(oldNodePath)
+	newParentPath := getParent(newNodePath)
+
+	fs.mu.RLock()
+	srcParent, srcOk := fs.lookupDirectory(oldParentPath)
+	destParent, destOk := fs.lookupDirectory(newParentPath)
+	fs.mu.RUnlock()
+
+	if srcOk {
+		if err := fs.ensureListed(srcParent, oldParentPath); err != nil {
+			return err
+		}
+	}
+	if destOk && destParent != srcParent {
+		if err := fs.ensureListed(destParent, newParentPath); err != nil {
+			return err
+		}
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if node, ok := fs.directories[oldPath]; ok {
+		return fs.renameDirectory(oldPath, newPath, oldNodePath, newNodePath, node)
+	}
+
+	return fs.renameFile(oldPath, newPath, oldNodePath, newNodePath)
+}
+
+func (fs *RAMFileSystem) renameFile(oldPath, newPath string, oldNodePath, newNodePath []string) error {
+	srcParent, ok := fs.lookupDirectory(getParent(oldNodePath))
+	if !ok {
+		return ErrorsFileNotFound
+	}
+
+	destParent, ok := fs.lookupDirectory(getParent(newNodePath))
+	if !ok {
+		return ErrorsNoParentDirectory
+	}
+
+	if _, ok := fs.directories[newPath]; ok {
+		return ErrorsIsDirectory
+	}
+
+	if srcParent == destParent {
+		srcParent.mu.Lock()
+		defer srcParent.mu.Unlock()
+	} else {
+		srcParent.mu.Lock()
+		defer srcParent.mu.Unlock()
+		destParent.mu.Lock()
+		defer destParent.mu.Unlock()
+	}
+
+	if srcParent.Write == false || destParent.Write == false {
+		return ErrorsNoWritePermission
+	}
+
+	oldName := oldNodePath[len(oldNodePath)-1]
+	newName := newNodePath[len(newNodePath)-1]
+
+	var file *File
+	var srcFiles []*File
+	for _, f := range srcParent.Files {
+		if f.Name == oldName {
+			file = f
+			continue
+		}
+		srcFiles = append(srcFiles, f)
+	}
+	if file == nil {
+		return ErrorsFileNotFound
+	}
+
+	var victim *File
+	var destFiles []*File
+	for _, f := range destParent.Files {
+		if f == file {
+			continue
+		}
+		if f.Name == newName {
+			victim = f
+			continue
+		}
+		destFiles = append(destFiles, f)
+	}
+
+	if victim != nil {
+		victim.mu.RLock()
+		writable := victim.Write
+		victim.mu.RUnlock()
+
+		if writable == false {
+			return ErrorsNoWritePermission
+		}
+	}
+
+	oldSrcFiles := srcParent.Files
+	oldDestFiles := destParent.Files
+
+	file.mu.Lock()
+	file.Name = newName
+	file.Modified = time.Now().UnixNano()
+	file.mu.Unlock()
+
+	destFiles = append(destFiles, file)
+
+	srcParent.Files = srcFiles
+	srcParent.Modified = time.Now().UnixNano()
+
+	destParent.Files = destFiles
+	destParent.Modified = time.Now().UnixNano()
+
+	fs.recordMutation(newPath, func() {
+		file.mu.Lock()
+		file.Name = oldName
+		file.mu.Unlock()
+
+		srcParent.mu.Lock()
+		srcParent.Files = oldSrcFiles
+		srcParent.mu.Unlock()
+
+		destParent.mu.Lock()
+		destParent.Files = oldDestFiles
+		destParent.mu.Unlock()
+	})
+
+	return nil
+}
+
+func (fs *RAMFileSystem) renameDirectory(oldPath, newPath string, oldNodePath, newNodePath []string, node *Directory) error {
+	srcParent, ok := fs.lookupDirectory(getParent(oldNodePath))
+	if !ok {
+		return ErrorsDirectoryNotFound
+	}
+
+	destParent, ok := fs.lookupDirectory(getParent(newNodePath))
+	if !ok {
+		return ErrorsNoParentDirectory
+	}
+
+	victim, hasVictim := fs.directories[newPath]
+	if hasVictim {
+		victim.mu.RLock()
+		empty := len(victim.Files) == 0 && len(victim.Directories) == 0
+		writable := victim.Write
+		victim.mu.RUnlock()
+
+		if !empty {
+			return ErrorsDirectoryNotEmpty
+		}
+		if writable == false {
+			return ErrorsNoWritePermission
+		}
+	}
+
+	if srcParent == destParent {
+		srcParent.mu.Lock()
+		defer srcParent.mu.Unlock()
+	} else {
+		srcParent.mu.Lock()
+		defer srcParent.mu.Unlock()
+		destParent.mu.Lock()
+		defer destParent.mu.Unlock()
+	}
+
+	if srcParent.Write == false || destParent.Write == false {
+		return ErrorsNoWritePermission
+	}
+
+	if node.Write == false {
+		return ErrorsNoWritePermission
+	}
+
+	newName := newNodePath[len(newNodePath)-1]
+
+	for _, f := range destParent.Files {
+		if f.Name == newName {
+			return ErrorsIsDirectory
+		}
+	}
+
+	var srcDirs []*Directory
+	for _, d := range srcParent.Directories {
+		if d != node {
+			srcDirs = append(srcDirs, d)
+		}
+	}
+
+	var destDirs []*Directory
+	for _, d := range destParent.Directories {
+		if d.Name != newName && d != node {
+			destDirs = append(destDirs, d)
+		}
+	}
+
+	moved := make(map[string]string)
+	for p := range fs.directories {
+		if p == oldPath {
+			moved[p] = newPath
+		} else if strings.HasPrefix(p, oldPath+"/") {
+			moved[p] = newPath + strings.TrimPrefix(p, oldPath)
+		}
+	}
+
+	oldSrcDirs := srcParent.Directories
+	oldDestDirs := destParent.Directories
+	oldEntries := make(map[string]*Directory, len(moved))
+	for p := range moved {
+		oldEntries[p] = fs.directories[p]
+	}
+
+	for p := range moved {
+		delete(fs.directories, p)
+	}
+	for p, newP := range moved {
+		fs.directories[newP] = oldEntries[p]
+	}
+
+	node.mu.Lock()
+	node.Name = newName
+	node.Modified = time.Now().UnixNano()
+	node.mu.Unlock()
+
+	destDirs = append(destDirs, node)
+
+	srcParent.Directories = srcDirs
+	srcParent.Modified = time.Now().UnixNano()
+
+	destParent.Directories = destDirs
+	destParent.Modified = time.Now().UnixNano()
+
+	fs.recordMutation(newPath, func() {
+		node.mu.Lock()
+		node.Name = oldNodePath[len(oldNodePath)-1]
+		node.mu.Unlock()
+
+		fs.mu.Lock()
+		for p := range moved {
+			delete(fs.directories, moved[p])
+		}
+		for p, n := range oldEntries {
+			fs.directories[p] = n
+		}
+		if hasVictim {
+			fs.directories[newPath] = victim
+		}
+		fs.mu.Unlock()
+
+		srcParent.mu.Lock()
+		srcParent.Directories = oldSrcDirs
+		srcParent.mu.Unlock()
+
+		destParent.mu.Lock()
+		destParent.Directories = oldDestDirs
+		destParent.mu.Unlock()
+	})
+
+	return nil
+}
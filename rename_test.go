@@ -0,0 +1,147 @@
+package ramfs
+
+import "testing"
+
+//TestRenameFile covers a plain file rename into a different directory
+func TestRenameFile(t *testing.T) {
+	fs := New()
+
+	if err := fs.Mkdir("/dst/"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := fs.WriteFile("/src.txt", []byte("hello")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := fs.Rename("/src.txt", "/dst/dst.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	if _, err := fs.FileGetContents("/src.txt"); err != ErrorsFileNotFound {
+		t.Fatalf("FileGetContents(/src.txt) = %v, want ErrorsFileNotFound", err)
+	}
+
+	content, err := fs.FileGetContents("/dst/dst.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/dst/dst.txt): %v", err)
+	}
+	if string(content) != "hello" {
+		t.Fatalf("content = %q, want %q", content, "hello")
+	}
+}
+
+//TestRenameDirectoryRekeysDescendants checks that renaming a directory moves every nested
+//directory entry in fs.directories along with it, not just the directory itself
+func TestRenameDirectoryRekeysDescendants(t *testing.T) {
+	fs := New()
+
+	if err := fs.Mkdir("/a/"); err != nil {
+		t.Fatalf("Mkdir /a: %v", err)
+	}
+	if err := fs.Mkdir("/a/b/"); err != nil {
+		t.Fatalf("Mkdir /a/b: %v", err)
+	}
+	if err := fs.WriteFile("/a/b/f.txt", []byte("x")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := fs.Rename("/a", "/z"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	if _, _, err := fs.Ls("/z/b"); err != nil {
+		t.Fatalf("Ls(/z/b): %v", err)
+	}
+	if _, _, err := fs.Ls("/a"); err != ErrorsDirectoryNotFound {
+		t.Fatalf("Ls(/a) = %v, want ErrorsDirectoryNotFound", err)
+	}
+
+	content, err := fs.FileGetContents("/z/b/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents(/z/b/f.txt): %v", err)
+	}
+	if string(content) != "x" {
+		t.Fatalf("content = %q, want %q", content, "x")
+	}
+}
+
+//TestRenameRejectsMoveIntoOwnSubtree is a regression test for a cycle bug where renaming a
+//directory into its own descendant silently corrupted the tree
+func TestRenameRejectsMoveIntoOwnSubtree(t *testing.T) {
+	fs := New()
+
+	if err := fs.Mkdir("/a/"); err != nil {
+		t.Fatalf("Mkdir /a: %v", err)
+	}
+	if err := fs.Mkdir("/a/b/"); err != nil {
+		t.Fatalf("Mkdir /a/b: %v", err)
+	}
+
+	if err := fs.Rename("/a", "/a/b/a"); err != ErrorsInvalidRename {
+		t.Fatalf("Rename(/a, /a/b/a) = %v, want ErrorsInvalidRename", err)
+	}
+	if err := fs.Rename("/a", "/a"); err != ErrorsInvalidRename {
+		t.Fatalf("Rename(/a, /a) = %v, want ErrorsInvalidRename", err)
+	}
+}
+
+//TestRenameFileOverwritesExistingFile checks that renaming onto an existing, writable file is
+//allowed and replaces it
+func TestRenameFileOverwritesExistingFile(t *testing.T) {
+	fs := New()
+
+	if err := fs.WriteFile("/src.txt", []byte("new")); err != nil {
+		t.Fatalf("WriteFile /src.txt: %v", err)
+	}
+	if err := fs.WriteFile("/dst.txt", []byte("old")); err != nil {
+		t.Fatalf("WriteFile /dst.txt: %v", err)
+	}
+
+	if err := fs.Rename("/src.txt", "/dst.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	content, err := fs.FileGetContents("/dst.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents: %v", err)
+	}
+	if string(content) != "new" {
+		t.Fatalf("content = %q, want %q", content, "new")
+	}
+}
+
+//TestRenameDirectoryOntoNonEmptyDirectoryFails checks that a non-empty destination directory
+//cannot be silently clobbered by a rename
+func TestRenameDirectoryOntoNonEmptyDirectoryFails(t *testing.T) {
+	fs := New()
+
+	if err := fs.Mkdir("/a/"); err != nil {
+		t.Fatalf("Mkdir /a: %v", err)
+	}
+	if err := fs.Mkdir("/b/"); err != nil {
+		t.Fatalf("Mkdir /b: %v", err)
+	}
+	if err := fs.WriteFile("/b/f.txt", []byte("x")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := fs.Rename("/a", "/b"); err != ErrorsDirectoryNotEmpty {
+		t.Fatalf("Rename(/a, /b) = %v, want ErrorsDirectoryNotEmpty", err)
+	}
+}
+
+//TestRenameFileOntoExistingDirectoryFails checks that a file can never replace a directory
+func TestRenameFileOntoExistingDirectoryFails(t *testing.T) {
+	fs := New()
+
+	if err := fs.WriteFile("/src.txt", []byte("x")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := fs.Mkdir("/dst/"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	if err := fs.Rename("/src.txt", "/dst"); err != ErrorsIsDirectory {
+		t.Fatalf("Rename(/src.txt, /dst) = %v, want ErrorsIsDirectory", err)
+	}
+}
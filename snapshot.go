@@ -0,0 +1,116 @@
+package ramfs
+
+import (
+	"crypto/sha256"
+	"sort"
+)
+
+//Snapshot is an immutable, point-in-time copy of every node in a RAMFileSystem, taken by Snapshot.
+//It holds no references back into the live tree, so later writes to the filesystem never affect
+//a Snapshot already taken
+type Snapshot struct {
+	entries map[string]snapshotEntry
+}
+
+type snapshotEntry struct {
+	isDir bool
+
+	read  bool
+	write bool
+
+	created  int64
+	modified int64
+
+	hash [sha256.Size]byte
+}
+
+//ChangeKind describes how a path differs between two snapshots
+type ChangeKind int
+
+const (
+	//ChangeCreated means the path exists in the newer snapshot but not the older one
+	ChangeCreated = 0
+	//ChangeModified means the path exists in both snapshots but its content or permissions differ
+	ChangeModified = 1
+	//ChangeDeleted means the path exists in the older snapshot but not the newer one
+	ChangeDeleted = 2
+)
+
+//Change is a single per-path difference produced by Diff
+type Change struct {
+	Path  string
+	Kind  ChangeKind
+	IsDir bool
+
+	BeforeHash [sha256.Size]byte
+	AfterHash  [sha256.Size]byte
+}
+
+//Snapshot walks the whole tree and returns an immutable copy of every node's permissions,
+//timestamps and content hash. A file belonging to a lazy-mounted directory that hasn't been
+//materialized yet (see MountLazy) snapshots with an empty content hash
+func (fs *RAMFileSystem) Snapshot() Snapshot {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	entries := make(map[string]snapshotEntry, len(fs.directories))
+
+	for dirPath, dir := range fs.directories {
+		dir.mu.RLock()
+
+		entries[dirPath] = snapshotEntry{
+			isDir:    true,
+			read:     dir.Read,
+			write:    dir.Write,
+			created:  dir.Created,
+			modified: dir.Modified,
+		}
+
+		for _, file := range dir.Files {
+			file.mu.RLock()
+
+			entries[joinPath(dirPath, file.Name)] = snapshotEntry{
+				read:     file.Read,
+				write:    file.Write,
+				created:  file.Created,
+				modified: file.Modified,
+				hash:     sha256.Sum256(file.Content()),
+			}
+
+			file.mu.RUnlock()
+		}
+
+		dir.mu.RUnlock()
+	}
+
+	return Snapshot{entries: entries}
+}
+
+//Diff compares two snapshots and returns every path that was created, modified or deleted
+//between a and b, sorted by path. A path is reported as modified if its content hash or its
+//Read/Write permissions differ
+func Diff(a, b Snapshot) []Change {
+	var changes []Change
+
+	for path, after := range b.entries {
+		before, ok := a.entries[path]
+		if !ok {
+			changes = append(changes, Change{Path: path, Kind: ChangeCreated, IsDir: after.isDir, AfterHash: after.hash})
+			continue
+		}
+
+		if before.hash != after.hash || before.read != after.read || before.write != after.write {
+			changes = append(changes, Change{Path: path, Kind: ChangeModified, IsDir: after.isDir, BeforeHash: before.hash, AfterHash: after.hash})
+		}
+	}
+
+	for path, before := range a.entries {
+		if _, ok := b.entries[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: ChangeDeleted, IsDir: before.isDir, BeforeHash: before.hash})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	return changes
+}
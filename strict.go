@@ -0,0 +1,90 @@
+package ramfs
+
+//pendingMutation is an unsynced change to the committed tree. undo reverts the mutation's
+//effect; it is only ever invoked by ResetToSyncedState, after the mutating call has returned.
+type pendingMutation struct {
+	path string
+	undo func()
+}
+
+//NewStrict creates a memory based file system in strict-sync mode, modelled on Pebble's
+//NewStrictMem. WriteFile/Mkdir/Rm are applied to the tree immediately (so reads observe them
+//right away, matching how a real page cache behaves) but are also recorded as "unsynced"; a
+//crash can be simulated at any point with ResetToSyncedState, which rolls every unsynced
+//mutation back. Call Sync or SyncAll to promote mutations so they survive a reset.
+func NewStrict(options ...Option) *RAMFileSystem {
+	fs := New(options...)
+	fs.strict = true
+
+	return fs
+}
+
+//recordMutation appends a pending mutation for path if the filesystem is in strict-sync mode
+func (fs *RAMFileSystem) recordMutation(path string, undo func()) {
+	if !fs.strict {
+		return
+	}
+
+	fs.pendingMu.Lock()
+	fs.pending = append(fs.pending, pendingMutation{path: path, undo: undo})
+	fs.pendingMu.Unlock()
+}
+
+//Sync promotes every unsynced mutation recorded against path, so it survives a future
+//ResetToSyncedState. It is a no-op (but discardable, per SetIgnoreSyncs) on a non-strict
+//filesystem, since nothing is ever pending on one.
+func (fs *RAMFileSystem) Sync(path string) error {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+
+	if fs.ignoreSyncs {
+		return nil
+	}
+
+	kept := fs.pending[:0]
+	for _, m := range fs.pending {
+		if m.path != path {
+			kept = append(kept, m)
+		}
+	}
+	fs.pending = kept
+
+	return nil
+}
+
+//SyncAll promotes every unsynced mutation, committing the filesystem's current state
+func (fs *RAMFileSystem) SyncAll() error {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+
+	if fs.ignoreSyncs {
+		return nil
+	}
+
+	fs.pending = nil
+
+	return nil
+}
+
+//ResetToSyncedState discards every unsynced mutation, reverting the tree to how it looked
+//after the last Sync/SyncAll. This simulates the effect of a crash before fsync.
+func (fs *RAMFileSystem) ResetToSyncedState() {
+	fs.pendingMu.Lock()
+	pending := fs.pending
+	fs.pending = nil
+	fs.pendingMu.Unlock()
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		pending[i].undo()
+	}
+}
+
+//SetIgnoreSyncs freezes the committed state: once set, Sync and SyncAll stop promoting
+//pending mutations, but WriteFile/Mkdir/Rm keep accepting (and recording as discardable)
+//writes on top of it. This is useful for fuzzing code paths that should tolerate a durability
+//layer that silently stops persisting anything.
+func (fs *RAMFileSystem) SetIgnoreSyncs(ignore bool) {
+	fs.pendingMu.Lock()
+	fs.ignoreSyncs = ignore
+	fs.pendingMu.Unlock()
+}
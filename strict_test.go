@@ -0,0 +1,127 @@
+package ramfs
+
+import "testing"
+
+//TestStrictResetToSyncedStateRevertsUnsyncedWrite is the crash-consistency scenario NewStrict
+//exists for: a write after the last Sync must disappear on ResetToSyncedState
+func TestStrictResetToSyncedStateRevertsUnsyncedWrite(t *testing.T) {
+	fs := NewStrict()
+
+	if err := fs.WriteFile("/f.txt", []byte("AAAAAAAAAA")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := fs.SyncAll(); err != nil {
+		t.Fatalf("SyncAll: %v", err)
+	}
+
+	if err := fs.WriteAt("/f.txt", []byte("BBBB"), 2); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+
+	content, err := fs.FileGetContents("/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents before reset: %v", err)
+	}
+	if string(content) != "AABBBBAAAA" {
+		t.Fatalf("content before reset = %q, want %q", content, "AABBBBAAAA")
+	}
+
+	fs.ResetToSyncedState()
+
+	content, err = fs.FileGetContents("/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents after reset: %v", err)
+	}
+	if string(content) != "AAAAAAAAAA" {
+		t.Fatalf("content after reset = %q, want %q", content, "AAAAAAAAAA")
+	}
+}
+
+//TestStrictResetUndoesMkdirAndRm checks that directory creation/removal, not just file content, is
+//rolled back by ResetToSyncedState
+func TestStrictResetUndoesMkdirAndRm(t *testing.T) {
+	fs := NewStrict()
+
+	if err := fs.Mkdir("/kept/"); err != nil {
+		t.Fatalf("Mkdir /kept: %v", err)
+	}
+	if err := fs.SyncAll(); err != nil {
+		t.Fatalf("SyncAll: %v", err)
+	}
+
+	if err := fs.Mkdir("/unsynced/"); err != nil {
+		t.Fatalf("Mkdir /unsynced: %v", err)
+	}
+	if err := fs.Rm("/kept/"); err != nil {
+		t.Fatalf("Rm /kept: %v", err)
+	}
+
+	fs.ResetToSyncedState()
+
+	if _, _, err := fs.Ls("/kept"); err != nil {
+		t.Fatalf("/kept should have survived the reset: %v", err)
+	}
+	if _, _, err := fs.Ls("/unsynced"); err != ErrorsDirectoryNotFound {
+		t.Fatalf("/unsynced should have been rolled back, Ls returned err=%v", err)
+	}
+}
+
+//TestSetIgnoreSyncsFreezesCommittedState checks that once ignored, Sync/SyncAll stop promoting
+//pending mutations, even though writes keep being accepted
+func TestSetIgnoreSyncsFreezesCommittedState(t *testing.T) {
+	fs := NewStrict()
+
+	if err := fs.WriteFile("/f.txt", []byte("A")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := fs.SyncAll(); err != nil {
+		t.Fatalf("SyncAll: %v", err)
+	}
+
+	fs.SetIgnoreSyncs(true)
+
+	if err := fs.WriteFile("/f.txt", []byte("B")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := fs.SyncAll(); err != nil {
+		t.Fatalf("SyncAll while ignoring: %v", err)
+	}
+
+	fs.ResetToSyncedState()
+
+	content, err := fs.FileGetContents("/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents: %v", err)
+	}
+	if string(content) != "A" {
+		t.Fatalf("content = %q, want %q (SyncAll should have been a no-op while ignoring)", content, "A")
+	}
+}
+
+//TestNonStrictSyncIsNoOp checks that Sync/SyncAll/ResetToSyncedState on a plain filesystem never
+//touch state, since nothing is ever recorded as pending on one
+func TestNonStrictSyncIsNoOp(t *testing.T) {
+	fs := New()
+
+	if err := fs.WriteFile("/f.txt", []byte("A")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := fs.Sync("/f.txt"); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if err := fs.SyncAll(); err != nil {
+		t.Fatalf("SyncAll: %v", err)
+	}
+
+	fs.ResetToSyncedState()
+
+	content, err := fs.FileGetContents("/f.txt")
+	if err != nil {
+		t.Fatalf("FileGetContents: %v", err)
+	}
+	if string(content) != "A" {
+		t.Fatalf("content = %q, want %q", content, "A")
+	}
+}